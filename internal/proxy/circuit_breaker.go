@@ -0,0 +1,161 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"go.uber.org/zap"
+)
+
+// circuitState is one of the three classic circuit breaker states.
+type circuitState int32
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker replaces the old binary unreachableQueryNodes set with a
+// proper closed/open/half-open state machine per query node, so a node that
+// trips open doesn't get slammed by every pending request the instant it's
+// declared reachable again (the old thundering-herd recovery behavior).
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	node  int64
+	state circuitState
+
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	openedAt             int64
+}
+
+func newCircuitBreaker(node int64) *circuitBreaker {
+	return &circuitBreaker{
+		node:  node,
+		state: circuitClosed,
+	}
+}
+
+// allow reports whether a request may currently be routed to this node. When
+// open, it also handles the open -> half-open transition once the cool-down
+// has elapsed, and only lets a small fraction of calls through as probes
+// while half-open.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitClosed:
+		return true
+	case circuitOpen:
+		coolDown := paramtable.Get().ProxyCfg.CircuitBreakerCoolDownPeriod.GetAsDuration(time.Millisecond)
+		if time.Now().UnixMilli()-cb.openedAt < coolDown.Milliseconds() {
+			return false
+		}
+		cb.transitionTo(circuitHalfOpen)
+		return cb.allowHalfOpenProbe()
+	case circuitHalfOpen:
+		return cb.allowHalfOpenProbe()
+	default:
+		return true
+	}
+}
+
+// allowHalfOpenProbe must be called with cb.mu held.
+func (cb *circuitBreaker) allowHalfOpenProbe() bool {
+	probeRate := paramtable.Get().ProxyCfg.CircuitBreakerHalfOpenProbeRate.GetAsFloat()
+	return rand.Float64() < probeRate
+}
+
+// reportSuccess records a successful RPC/health-check against the node.
+func (cb *circuitBreaker) reportSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitClosed:
+		cb.consecutiveFailures = 0
+	case circuitHalfOpen:
+		cb.consecutiveSuccesses++
+		threshold := paramtable.Get().ProxyCfg.CircuitBreakerHalfOpenSuccessThreshold.GetAsInt()
+		if cb.consecutiveSuccesses >= threshold {
+			cb.transitionTo(circuitClosed)
+		}
+	}
+}
+
+// reportFailure records a failed RPC/health-check against the node.
+func (cb *circuitBreaker) reportFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitClosed:
+		cb.consecutiveFailures++
+		threshold := paramtable.Get().ProxyCfg.CircuitBreakerFailureThreshold.GetAsInt()
+		if cb.consecutiveFailures >= threshold {
+			cb.transitionTo(circuitOpen)
+		}
+	case circuitHalfOpen:
+		// a probe failed, the node isn't actually recovered yet
+		cb.transitionTo(circuitOpen)
+	}
+}
+
+// transitionTo must be called with cb.mu held.
+func (cb *circuitBreaker) transitionTo(to circuitState) {
+	from := cb.state
+	if from == to {
+		return
+	}
+	cb.state = to
+	cb.consecutiveFailures = 0
+	cb.consecutiveSuccesses = 0
+	if to == circuitOpen {
+		cb.openedAt = time.Now().UnixMilli()
+	}
+
+	log.Info("query node circuit breaker state transition",
+		zap.Int64("nodeID", cb.node), zap.String("from", from.String()), zap.String("to", to.String()))
+	metrics.ProxyCircuitBreakerState.WithLabelValues(strconv.FormatInt(cb.node, 10)).Set(float64(to))
+}
+
+func (cb *circuitBreaker) currentState() circuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
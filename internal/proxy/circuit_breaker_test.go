@@ -0,0 +1,57 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	cb := newCircuitBreaker(1)
+	assert.Equal(t, circuitClosed, cb.currentState())
+
+	// threshold is read from paramtable in reportFailure; whatever it is
+	// configured to, enough consecutive failures must trip the breaker.
+	for i := 0; i < 1000 && cb.currentState() == circuitClosed; i++ {
+		cb.reportFailure()
+	}
+
+	assert.Equal(t, circuitOpen, cb.currentState())
+	assert.False(t, cb.allow())
+}
+
+func TestCircuitBreaker_HalfOpenFailureReopens(t *testing.T) {
+	cb := newCircuitBreaker(1)
+	cb.transitionTo(circuitHalfOpen)
+	assert.Equal(t, circuitHalfOpen, cb.currentState())
+
+	cb.reportFailure()
+	assert.Equal(t, circuitOpen, cb.currentState())
+}
+
+func TestCircuitBreaker_HalfOpenClosesAfterEnoughSuccesses(t *testing.T) {
+	cb := newCircuitBreaker(1)
+	cb.transitionTo(circuitHalfOpen)
+
+	for i := 0; i < 1000 && cb.currentState() == circuitHalfOpen; i++ {
+		cb.reportSuccess()
+	}
+
+	assert.Equal(t, circuitClosed, cb.currentState())
+}
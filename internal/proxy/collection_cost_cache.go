@@ -0,0 +1,124 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"go.uber.org/atomic"
+)
+
+// defaultCollectionCostCacheCapacity bounds the number of (node, collection)
+// entries LookAsideBalancer keeps around when paramtable doesn't configure one.
+const defaultCollectionCostCacheCapacity = 4096
+
+// nodeCollectionKey identifies a single query node serving a single
+// collection, the granularity LookAsideBalancer scores requests at once
+// per-collection metrics are available.
+type nodeCollectionKey struct {
+	node         int64
+	collectionID int64
+}
+
+// collectionCostEntry is the per-(node, collection) bookkeeping LookAsideBalancer
+// needs: the latest reported cost metrics and the outstanding NQ this proxy
+// has already sent but not yet gotten a response for.
+type collectionCostEntry struct {
+	mu          sync.Mutex
+	cost        *internalpb.CostAggregation
+	executingNQ *atomic.Int64
+}
+
+func (e *collectionCostEntry) setCost(cost *internalpb.CostAggregation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cost = cost
+}
+
+func (e *collectionCostEntry) getCost() *internalpb.CostAggregation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cost
+}
+
+// collectionCostCache is a small LRU keyed by (node, collection) so that a
+// long-running proxy talking to many collections doesn't grow this
+// bookkeeping without bound.
+type collectionCostCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[nodeCollectionKey]*list.Element
+}
+
+type collectionCostCacheElement struct {
+	key   nodeCollectionKey
+	entry *collectionCostEntry
+}
+
+func newCollectionCostCache(capacity int) *collectionCostCache {
+	if capacity <= 0 {
+		capacity = defaultCollectionCostCacheCapacity
+	}
+	return &collectionCostCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[nodeCollectionKey]*list.Element),
+	}
+}
+
+// get returns the entry for key without creating one, bumping it to
+// most-recently-used on a hit.
+func (c *collectionCostCache) get(key nodeCollectionKey) (*collectionCostEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*collectionCostCacheElement).entry, true
+}
+
+// getOrCreate returns the entry for key, creating an empty one and evicting
+// the least-recently-used entry if the cache is at capacity.
+func (c *collectionCostCache) getOrCreate(key nodeCollectionKey) *collectionCostEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*collectionCostCacheElement).entry
+	}
+
+	entry := &collectionCostEntry{executingNQ: atomic.NewInt64(0)}
+	elem := c.ll.PushFront(&collectionCostCacheElement{key: key, entry: entry})
+	c.items[key] = elem
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*collectionCostCacheElement).key)
+		}
+	}
+
+	return entry
+}
@@ -0,0 +1,93 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionCostCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newCollectionCostCache(2)
+
+	keyA := nodeCollectionKey{node: 1, collectionID: 10}
+	keyB := nodeCollectionKey{node: 2, collectionID: 10}
+	keyC := nodeCollectionKey{node: 3, collectionID: 10}
+
+	cache.getOrCreate(keyA)
+	cache.getOrCreate(keyB)
+	// cache is now full with [B, A] (B most recent); adding C should evict A.
+	cache.getOrCreate(keyC)
+
+	_, ok := cache.get(keyA)
+	assert.False(t, ok, "least-recently-used entry should have been evicted")
+
+	_, ok = cache.get(keyB)
+	assert.True(t, ok)
+
+	_, ok = cache.get(keyC)
+	assert.True(t, ok)
+}
+
+func TestCollectionCostCache_GetPromotesToMostRecentlyUsed(t *testing.T) {
+	cache := newCollectionCostCache(2)
+
+	keyA := nodeCollectionKey{node: 1, collectionID: 10}
+	keyB := nodeCollectionKey{node: 2, collectionID: 10}
+	keyC := nodeCollectionKey{node: 3, collectionID: 10}
+
+	cache.getOrCreate(keyA)
+	cache.getOrCreate(keyB)
+
+	// touching A via get() should make B the least-recently-used entry.
+	_, ok := cache.get(keyA)
+	assert.True(t, ok)
+
+	cache.getOrCreate(keyC)
+
+	_, ok = cache.get(keyB)
+	assert.False(t, ok, "entry not touched since before the eviction should be gone")
+
+	_, ok = cache.get(keyA)
+	assert.True(t, ok, "recently touched entry should have survived the eviction")
+}
+
+// TestCollectionCostCache_EvictedEntryGetsFreshCounterOnRecreate documents the
+// desync CancelWorkload can hit when an entry is evicted while a request is
+// still outstanding against it: the Add landed on the evicted *atomic.Int64,
+// so a getOrCreate after eviction hands back a brand new counter starting
+// from zero rather than the one still carrying that outstanding NQ.
+func TestCollectionCostCache_EvictedEntryGetsFreshCounterOnRecreate(t *testing.T) {
+	cache := newCollectionCostCache(1)
+
+	key := nodeCollectionKey{node: 1, collectionID: 10}
+	evicted := nodeCollectionKey{node: 2, collectionID: 10}
+
+	entry := cache.getOrCreate(key)
+	entry.executingNQ.Add(5)
+
+	// evict key's entry by filling the single slot with another key.
+	cache.getOrCreate(evicted)
+	_, ok := cache.get(key)
+	assert.False(t, ok)
+
+	recreated := cache.getOrCreate(key)
+	assert.NotSame(t, entry, recreated)
+	assert.Equal(t, int64(0), recreated.executingNQ.Load(),
+		"a getOrCreate after eviction starts a fresh counter, losing the outstanding NQ recorded on the evicted entry")
+}
@@ -0,0 +1,148 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// HedgingEnabledSearchParamKey is the search/query request param a caller
+// sets to opt a collection's requests into latency-aware hedging, e.g.
+// {"hedge": "true"} in SearchRequest.SearchParams.
+const HedgingEnabledSearchParamKey = "hedge"
+
+// IsHedgingEnabled reports whether the request-level params opt into hedging.
+func IsHedgingEnabled(params []*commonpb.KeyValuePair) bool {
+	for _, kv := range params {
+		if kv.GetKey() == HedgingEnabledSearchParamKey {
+			enabled, _ := strconv.ParseBool(kv.GetValue())
+			return enabled
+		}
+	}
+	return false
+}
+
+// hedgedResult carries a shard-level search/query call's outcome back to
+// whichever of ExecuteWithHedging's primary/backup goroutines finishes first.
+type hedgedResult[T any] struct {
+	val  T
+	err  error
+	node int64
+}
+
+// ExecuteWithHedging dispatches call against the best replica balancer picks
+// for collectionID. When hedgingEnabled and a second replica is available, a
+// backup request is raced against the next-best replica once the primary has
+// been outstanding longer than the delay LookAsideBalancer suggests; whichever
+// finishes first wins, and CancelWorkload is invoked for the loser.
+//
+// TODO: nothing in this tree calls this yet. The shard-level search/query
+// dispatcher (the code that should call this per shard with
+// IsHedgingEnabled(req.GetSearchParams()) as hedgingEnabled and a closure
+// issuing the QueryNode RPC as call) does not exist in this checkout, so the
+// request's "integrate this with the shard-level search/query path" ask is
+// NOT done — this function and SelectNodesForHedging are unreachable outside
+// of hedging_test.go until that dispatcher is wired up.
+func ExecuteWithHedging[T any](
+	ctx context.Context,
+	balancer *LookAsideBalancer,
+	availableNodes []int64,
+	cost int64,
+	collectionID int64,
+	hedgingEnabled bool,
+	call func(ctx context.Context, node int64) (T, error),
+) (T, error) {
+	log := log.Ctx(ctx)
+
+	if !hedgingEnabled || len(availableNodes) < 2 {
+		node, err := balancer.SelectNode(ctx, availableNodes, cost, collectionID)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		val, err := call(ctx, node)
+		balancer.CancelWorkload(node, cost, collectionID)
+		return val, err
+	}
+
+	nodes, delay, err := balancer.SelectNodesForHedging(ctx, availableNodes, cost, collectionID, 2)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	primary := nodes[0]
+
+	resultCh := make(chan hedgedResult[T], 2)
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	go func() {
+		val, err := call(primaryCtx, primary)
+		resultCh <- hedgedResult[T]{val: val, err: err, node: primary}
+	}()
+
+	if len(nodes) < 2 {
+		// no second replica available to hedge against, just wait for the primary
+		r := <-resultCh
+		balancer.CancelWorkload(primary, cost, collectionID)
+		return r.val, r.err
+	}
+	backup := nodes[1]
+
+	metrics.ProxyHedgedRequestsTotal.WithLabelValues(strconv.FormatInt(collectionID, 10)).Inc()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case r := <-resultCh:
+		balancer.CancelWorkload(primary, cost, collectionID)
+		return r.val, r.err
+	case <-timer.C:
+	}
+
+	log.Debug("primary replica exceeded hedge delay, dispatching backup",
+		zap.Int64("collectionID", collectionID), zap.Int64("primary", primary), zap.Int64("backup", backup), zap.Duration("delay", delay))
+
+	balancer.BookWorkload(backup, cost, collectionID)
+
+	backupCtx, cancelBackup := context.WithCancel(ctx)
+	defer cancelBackup()
+	go func() {
+		val, err := call(backupCtx, backup)
+		resultCh <- hedgedResult[T]{val: val, err: err, node: backup}
+	}()
+
+	winner := <-resultCh
+
+	balancer.CancelWorkload(primary, cost, collectionID)
+	balancer.CancelWorkload(backup, cost, collectionID)
+
+	winnerLabel := "primary"
+	if winner.node == backup {
+		winnerLabel = "backup"
+	}
+	metrics.ProxyHedgedWinsTotal.WithLabelValues(strconv.FormatInt(collectionID, 10), winnerLabel).Inc()
+
+	return winner.val, winner.err
+}
@@ -0,0 +1,79 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+)
+
+func TestIsHedgingEnabled(t *testing.T) {
+	assert.False(t, IsHedgingEnabled(nil))
+	assert.True(t, IsHedgingEnabled([]*commonpb.KeyValuePair{{Key: HedgingEnabledSearchParamKey, Value: "true"}}))
+	assert.False(t, IsHedgingEnabled([]*commonpb.KeyValuePair{{Key: HedgingEnabledSearchParamKey, Value: "false"}}))
+}
+
+func TestExecuteWithHedging_DisabledUsesPrimaryOnly(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+	ctx := context.Background()
+
+	called := make([]int64, 0)
+	val, err := ExecuteWithHedging(ctx, balancer, []int64{1, 2}, 1, 10, false, func(ctx context.Context, node int64) (int, error) {
+		called = append(called, node)
+		return 42, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, val)
+	assert.Len(t, called, 1)
+}
+
+func TestExecuteWithHedging_BackupWinsWhenPrimaryIsSlow(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	primary := int64(-1)
+
+	val, err := ExecuteWithHedging(ctx, balancer, []int64{1, 2}, 1, 10, true, func(ctx context.Context, node int64) (int, error) {
+		mu.Lock()
+		if primary == -1 {
+			primary = node
+		}
+		isPrimary := node == primary
+		mu.Unlock()
+
+		if isPrimary {
+			// the primary replica is slow; the backup should win the race
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			return 1, nil
+		}
+		return 2, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+}
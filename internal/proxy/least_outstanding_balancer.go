@@ -0,0 +1,84 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// LeastOutstandingBalancer always routes to the node with the fewest
+// in-flight NQ, ignoring the response-time/queue-size cost metrics that
+// LookAsideBalancer's C3 score factors in. It's O(len(availableNodes)) per
+// SelectNode call, same as the full scan balancer, but much cheaper to
+// compute.
+type LeastOutstandingBalancer struct {
+	// query node -> total nq of requests which already sent but response hasn't received
+	executingTaskTotalNQ *typeutil.ConcurrentMap[int64, *atomic.Int64]
+}
+
+func NewLeastOutstandingBalancer() *LeastOutstandingBalancer {
+	return &LeastOutstandingBalancer{
+		executingTaskTotalNQ: typeutil.NewConcurrentMap[int64, *atomic.Int64](),
+	}
+}
+
+func (b *LeastOutstandingBalancer) Start(ctx context.Context) {}
+
+func (b *LeastOutstandingBalancer) Close() {}
+
+func (b *LeastOutstandingBalancer) SelectNode(ctx context.Context, availableNodes []int64, cost int64, collectionID int64) (int64, error) {
+	targetNode := int64(-1)
+	targetNQ := int64(-1)
+	for _, node := range availableNodes {
+		executingNQ, ok := b.executingTaskTotalNQ.Get(node)
+		if !ok {
+			executingNQ = atomic.NewInt64(0)
+			b.executingTaskTotalNQ.Insert(node, executingNQ)
+		}
+
+		nq := executingNQ.Load()
+		if targetNode == -1 || nq < targetNQ {
+			targetNode = node
+			targetNQ = nq
+		}
+	}
+
+	if targetNode == -1 {
+		return -1, merr.WrapErrServiceUnavailable("no available nodes to select")
+	}
+
+	totalNQ, _ := b.executingTaskTotalNQ.Get(targetNode)
+	totalNQ.Add(cost)
+
+	return targetNode, nil
+}
+
+func (b *LeastOutstandingBalancer) CancelWorkload(node int64, nq int64, collectionID int64) {
+	totalNQ, ok := b.executingTaskTotalNQ.Get(node)
+	if ok {
+		totalNQ.Sub(nq)
+	}
+}
+
+func (b *LeastOutstandingBalancer) UpdateCostMetrics(node int64, cost *internalpb.CostAggregation, collectionID int64) {
+}
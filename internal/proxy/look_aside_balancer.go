@@ -19,6 +19,7 @@ package proxy
 import (
 	"context"
 	"math"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -52,7 +53,22 @@ type LookAsideBalancer struct {
 	// query node -> total nq of requests which already send but response hasn't received
 	executingTaskTotalNQ *typeutil.ConcurrentMap[int64, *atomic.Int64]
 
-	unreachableQueryNodes *typeutil.ConcurrentSet[int64]
+	// query node -> EWMA of queue length, service time and send/receive rate, used by
+	// the full C3 scoring function and the rate-control layer.
+	nodeCostStats *typeutil.ConcurrentMap[int64, *nodeCostStat]
+
+	// cluster size(n) observed on the most recent SelectNode call, used to
+	// estimate q̂_s = 1 + os·n + q_s when a cost report comes back.
+	lastClusterSize *atomic.Int64
+
+	// (node, collection) -> per-collection cost metrics and outstanding NQ, so a
+	// slow collection on a node doesn't pollute the score of other collections
+	// sharing that node. Bounded by an LRU since a proxy can see many collections
+	// over its lifetime.
+	collectionCostCache *collectionCostCache
+
+	// query node -> circuit breaker tracking that node's reachability
+	circuitBreakers *typeutil.ConcurrentMap[int64, *circuitBreaker]
 
 	closeCh   chan struct{}
 	closeOnce sync.Once
@@ -61,12 +77,15 @@ type LookAsideBalancer struct {
 
 func NewLookAsideBalancer(clientMgr shardClientMgr) *LookAsideBalancer {
 	balancer := &LookAsideBalancer{
-		clientMgr:             clientMgr,
-		metricsMap:            typeutil.NewConcurrentMap[int64, *internalpb.CostAggregation](),
-		metricsUpdateTs:       typeutil.NewConcurrentMap[int64, int64](),
-		executingTaskTotalNQ:  typeutil.NewConcurrentMap[int64, *atomic.Int64](),
-		unreachableQueryNodes: typeutil.NewConcurrentSet[int64](),
-		closeCh:               make(chan struct{}),
+		clientMgr:            clientMgr,
+		metricsMap:           typeutil.NewConcurrentMap[int64, *internalpb.CostAggregation](),
+		metricsUpdateTs:      typeutil.NewConcurrentMap[int64, int64](),
+		executingTaskTotalNQ: typeutil.NewConcurrentMap[int64, *atomic.Int64](),
+		nodeCostStats:        typeutil.NewConcurrentMap[int64, *nodeCostStat](),
+		lastClusterSize:      atomic.NewInt64(0),
+		collectionCostCache:  newCollectionCostCache(paramtable.Get().ProxyCfg.CostMetricsMaxCollectionCacheSize.GetAsInt()),
+		circuitBreakers:      typeutil.NewConcurrentMap[int64, *circuitBreaker](),
+		closeCh:              make(chan struct{}),
 	}
 
 	return balancer
@@ -84,26 +103,35 @@ func (b *LookAsideBalancer) Close() {
 	})
 }
 
-func (b *LookAsideBalancer) SelectNode(ctx context.Context, availableNodes []int64, cost int64) (int64, error) {
+func (b *LookAsideBalancer) SelectNode(ctx context.Context, availableNodes []int64, cost int64, collectionID int64) (int64, error) {
 	log := log.Ctx(ctx).WithRateGroup("proxy.LookAsideBalancer", 1, 60)
+	clusterSize := len(availableNodes)
+	b.lastClusterSize.Store(int64(clusterSize))
 	targetNode := int64(-1)
 	targetScore := float64(math.MaxFloat64)
+	// fallback candidate used when every node is currently being rate-throttled,
+	// so SelectNode never blocks the request entirely.
+	throttledNode := int64(-1)
+	throttledScore := float64(math.MaxFloat64)
 	for _, node := range availableNodes {
-		if b.unreachableQueryNodes.Contain(node) {
-			log.RatedWarn(5, "query node  is unreachable, skip it",
+		if !b.getOrCreateCircuitBreaker(node).allow() {
+			log.RatedWarn(5, "query node circuit breaker is open, skip it",
 				zap.Int64("nodeID", node))
 			continue
 		}
 
-		cost, _ := b.metricsMap.Get(node)
-		executingNQ, ok := b.executingTaskTotalNQ.Get(node)
-		if !ok {
-			executingNQ = atomic.NewInt64(0)
-			b.executingTaskTotalNQ.Insert(node, executingNQ)
-		}
+		cost, executingNQ := b.getNodeCollectionMetrics(node, collectionID)
+
+		score := b.calculateScore(node, cost, executingNQ.Load(), clusterSize)
+		metrics.ProxyWorkLoadScore.WithLabelValues(strconv.FormatInt(node, 10), strconv.FormatInt(collectionID, 10)).Set(score)
 
-		score := b.calculateScore(node, cost, executingNQ.Load())
-		metrics.ProxyWorkLoadScore.WithLabelValues(strconv.FormatInt(node, 10)).Set(score)
+		if b.isNodeRateThrottled(node) {
+			if throttledNode == -1 || score < throttledScore {
+				throttledScore = score
+				throttledNode = node
+			}
+			continue
+		}
 
 		if targetNode == -1 || score < targetScore {
 			targetScore = score
@@ -111,35 +139,246 @@ func (b *LookAsideBalancer) SelectNode(ctx context.Context, availableNodes []int
 		}
 	}
 
+	if targetNode == -1 {
+		targetNode = throttledNode
+	}
+
 	if targetNode == -1 {
 		return -1, merr.WrapErrServiceUnavailable("all available nodes are unreachable")
 	}
 
-	// update executing task cost
-	totalNQ, _ := b.executingTaskTotalNQ.Get(targetNode)
+	// update executing task cost, both the per-collection counter used for scoring
+	// and the per-node aggregate used as a fallback and by the rate-control layer
+	totalNQ, ok := b.executingTaskTotalNQ.Get(targetNode)
+	if !ok {
+		totalNQ = atomic.NewInt64(0)
+		b.executingTaskTotalNQ.Insert(targetNode, totalNQ)
+	}
 	totalNQ.Add(cost)
+	b.collectionCostCache.getOrCreate(nodeCollectionKey{node: targetNode, collectionID: collectionID}).executingNQ.Add(cost)
+	b.getOrCreateNodeCostStat(targetNode).recordSend()
 
 	return targetNode, nil
 }
 
+// SelectNodesForHedging returns up to k candidates ordered by C3 score
+// (best first) together with a suggested hedge delay: once the primary pick
+// has been outstanding for longer than the delay, callers should dispatch a
+// backup request to the next candidate. The delay is derived from the p95 of
+// the candidates' recently reported response times.
+func (b *LookAsideBalancer) SelectNodesForHedging(ctx context.Context, availableNodes []int64, cost int64, collectionID int64, k int) ([]int64, time.Duration, error) {
+	log := log.Ctx(ctx).WithRateGroup("proxy.LookAsideBalancer", 1, 60)
+	clusterSize := len(availableNodes)
+	b.lastClusterSize.Store(int64(clusterSize))
+
+	type candidate struct {
+		node         int64
+		score        float64
+		responseTime int64
+		throttled    bool
+	}
+
+	candidates := make([]candidate, 0, len(availableNodes))
+	for _, node := range availableNodes {
+		if !b.getOrCreateCircuitBreaker(node).allow() {
+			log.RatedWarn(5, "query node circuit breaker is open, skip it for hedging",
+				zap.Int64("nodeID", node))
+			continue
+		}
+
+		nodeCost, executingNQ := b.getNodeCollectionMetrics(node, collectionID)
+		score := b.calculateScore(node, nodeCost, executingNQ.Load(), clusterSize)
+		candidates = append(candidates, candidate{
+			node:         node,
+			score:        score,
+			responseTime: nodeCost.GetResponseTime(),
+			throttled:    b.isNodeRateThrottled(node),
+		})
+	}
+
+	if len(candidates) == 0 {
+		return nil, 0, merr.WrapErrServiceUnavailable("all available nodes are unreachable")
+	}
+
+	// same fallback as SelectNode: prefer nodes that aren't currently
+	// rate-throttled, only reaching into throttled candidates to fill out k
+	// if there aren't enough healthy ones to hedge against.
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].throttled != candidates[j].throttled {
+			return !candidates[i].throttled
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+	top := candidates[:k]
+
+	nodes := make([]int64, len(top))
+	responseTimes := make([]int64, 0, len(top))
+	for i, c := range top {
+		nodes[i] = c.node
+		if c.responseTime > 0 {
+			responseTimes = append(responseTimes, c.responseTime)
+		}
+	}
+
+	// reserve the workload bookkeeping for the primary pick, same as SelectNode does
+	b.BookWorkload(nodes[0], cost, collectionID)
+
+	return nodes, p95Millis(responseTimes), nil
+}
+
+// BookWorkload records cost as outstanding NQ against node for collectionID
+// and marks a send on its rate-control stats, the same bookkeeping SelectNode
+// does for the node it returns. Callers that dispatch a request to a node
+// without going through SelectNode/SelectNodesForHedging first — e.g.
+// ExecuteWithHedging launching a backup request — must call this so the
+// later CancelWorkload has a matching entry to subtract from.
+func (b *LookAsideBalancer) BookWorkload(node int64, cost int64, collectionID int64) {
+	totalNQ, ok := b.executingTaskTotalNQ.Get(node)
+	if !ok {
+		totalNQ = atomic.NewInt64(0)
+		b.executingTaskTotalNQ.Insert(node, totalNQ)
+	}
+	totalNQ.Add(cost)
+	b.collectionCostCache.getOrCreate(nodeCollectionKey{node: node, collectionID: collectionID}).executingNQ.Add(cost)
+	b.getOrCreateNodeCostStat(node).recordSend()
+}
+
+// p95Millis returns the 95th percentile of samples (reported in milliseconds)
+// as a time.Duration, or the configured default hedge delay if there aren't
+// enough samples yet.
+func p95Millis(samples []int64) time.Duration {
+	if len(samples) == 0 {
+		return paramtable.Get().ProxyCfg.DefaultHedgeDelay.GetAsDuration(time.Millisecond)
+	}
+
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+
+	return time.Duration(sorted[idx]) * time.Millisecond
+}
+
+// getNodeCollectionMetrics returns the cost metrics and outstanding NQ counter
+// scoped to (node, collectionID), falling back to the node-wide aggregate when
+// no per-collection data has been reported for this pair yet.
+func (b *LookAsideBalancer) getNodeCollectionMetrics(node int64, collectionID int64) (*internalpb.CostAggregation, *atomic.Int64) {
+	if entry, ok := b.collectionCostCache.get(nodeCollectionKey{node: node, collectionID: collectionID}); ok {
+		if cost := entry.getCost(); cost != nil {
+			return cost, entry.executingNQ
+		}
+	}
+
+	cost, _ := b.metricsMap.Get(node)
+	executingNQ, ok := b.executingTaskTotalNQ.Get(node)
+	if !ok {
+		executingNQ = atomic.NewInt64(0)
+		b.executingTaskTotalNQ.Insert(node, executingNQ)
+	}
+	return cost, executingNQ
+}
+
 // when task canceled, should reduce executing total nq cost
-func (b *LookAsideBalancer) CancelWorkload(node int64, nq int64) {
+func (b *LookAsideBalancer) CancelWorkload(node int64, nq int64, collectionID int64) {
 	totalNQ, ok := b.executingTaskTotalNQ.Get(node)
 	if ok {
 		totalNQ.Sub(nq)
 	}
+	b.collectionCostCache.getOrCreate(nodeCollectionKey{node: node, collectionID: collectionID}).executingNQ.Sub(nq)
 }
 
 // UpdateCostMetrics used for cache some metrics of recent search/query cost
-func (b *LookAsideBalancer) UpdateCostMetrics(node int64, cost *internalpb.CostAggregation) {
-	// cache the latest query node cost metrics for updating the score
+func (b *LookAsideBalancer) UpdateCostMetrics(node int64, cost *internalpb.CostAggregation, collectionID int64) {
+	// cache the latest query node cost metrics for updating the score, both as the
+	// node-wide aggregate (used as a fallback) and scoped to this collection
 	b.metricsMap.Insert(node, cost)
 	b.metricsUpdateTs.Insert(node, time.Now().UnixMilli())
+	b.collectionCostCache.getOrCreate(nodeCollectionKey{node: node, collectionID: collectionID}).setCost(cost)
+	// a cost report means node just completed a request successfully
+	b.getOrCreateCircuitBreaker(node).reportSuccess()
+
+	executingNQ, ok := b.executingTaskTotalNQ.Get(node)
+	outstanding := int64(0)
+	if ok {
+		outstanding = executingNQ.Load()
+	}
+	clusterSize := int(b.lastClusterSize.Load())
+	if clusterSize == 0 {
+		clusterSize = 1
+	}
+	b.getOrCreateNodeCostStat(node).recordReceive(outstanding, clusterSize, cost, metricsEWMAAlpha())
+
+	nodeLabel := strconv.FormatInt(node, 10)
+	qHat, muHatInv, sendRate, receiveRate := b.getOrCreateNodeCostStat(node).snapshot()
+	metrics.ProxyNodeQueueLength.WithLabelValues(nodeLabel).Set(qHat)
+	metrics.ProxyNodeServiceTime.WithLabelValues(nodeLabel).Set(muHatInv)
+	metrics.ProxyNodeSendRate.WithLabelValues(nodeLabel).Set(sendRate)
+	metrics.ProxyNodeReceiveRate.WithLabelValues(nodeLabel).Set(receiveRate)
+}
+
+// getOrCreateNodeCostStat returns the EWMA bookkeeping used by the C3 score and
+// the rate-control layer, creating it lazily on first use.
+func (b *LookAsideBalancer) getOrCreateNodeCostStat(node int64) *nodeCostStat {
+	stat, ok := b.nodeCostStats.Get(node)
+	if !ok {
+		stat = newNodeCostStat()
+		b.nodeCostStats.Insert(node, stat)
+	}
+	return stat
 }
 
-// calculateScore compute the query node's workload score
+// getOrCreateCircuitBreaker returns the circuit breaker tracking node's
+// reachability, creating one in the closed state on first use.
+func (b *LookAsideBalancer) getOrCreateCircuitBreaker(node int64) *circuitBreaker {
+	cb, ok := b.circuitBreakers.Get(node)
+	if !ok {
+		cb = newCircuitBreaker(node)
+		b.circuitBreakers.Insert(node, cb)
+	}
+	return cb
+}
+
+// ReportFailure lets callers that observed an RPC failure against node (e.g. a
+// shard leader search/query call) feed that signal into node's circuit
+// breaker, on top of the active checkQueryNodeHealthLoop probes.
+func (b *LookAsideBalancer) ReportFailure(node int64, err error) {
+	if err == nil {
+		return
+	}
+	b.getOrCreateCircuitBreaker(node).reportFailure()
+}
+
+// isNodeRateThrottled reports whether node's observed send rate currently
+// exceeds its estimated service rate, in which case new selections should
+// prefer another node until the backlog drains.
+func (b *LookAsideBalancer) isNodeRateThrottled(node int64) bool {
+	return b.getOrCreateNodeCostStat(node).isThrottled()
+}
+
+// metricsEWMAAlpha returns the smoothing factor used for all the per-node
+// EWMAs (queue length, service time, send/receive rate).
+func metricsEWMAAlpha() float64 {
+	return paramtable.Get().ProxyCfg.CostMetricsEWMAAlpha.GetAsFloat()
+}
+
+// calculateScore computes the query node's workload score using the full C3
+// scheme: Ψ_s = R_s − 1/μ̂_s + (q̂_s)^3 / μ̂_s, where R_s is the observed
+// response time, 1/μ̂_s is the EWMA of the service time, and
+// q̂_s = 1 + os·n + q_s is the EWMA of the estimated queue length (os is the
+// outstanding requests this proxy has sent to the node, n is the cluster
+// size, and q_s is the node-reported queue length).
 // https://www.usenix.org/conference/nsdi15/technical-sessions/presentation/suresh
-func (b *LookAsideBalancer) calculateScore(node int64, cost *internalpb.CostAggregation, executingNQ int64) float64 {
+func (b *LookAsideBalancer) calculateScore(node int64, cost *internalpb.CostAggregation, executingNQ int64, clusterSize int) float64 {
 	if cost == nil || cost.ResponseTime == 0 || cost.ServiceTime == 0 {
 		return math.Pow(float64(1+executingNQ), 3.0)
 	}
@@ -151,8 +390,17 @@ func (b *LookAsideBalancer) calculateScore(node int64, cost *internalpb.CostAggr
 		return 0
 	}
 
-	executeSpeed := float64(cost.ResponseTime) - float64(cost.ServiceTime)
-	workload := math.Pow(float64(1+cost.TotalNQ+executingNQ), 3.0) * float64(cost.ServiceTime)
+	qHat, muHatInv, _, _ := b.getOrCreateNodeCostStat(node).snapshot()
+	if muHatInv <= 0 {
+		// no EWMA sample yet, fall back to the instantaneous metrics
+		muHatInv = float64(cost.ServiceTime)
+	}
+	if qHat <= 0 {
+		qHat = estimatedQueueLength(executingNQ, cost.TotalNQ, clusterSize)
+	}
+
+	executeSpeed := float64(cost.ResponseTime) - muHatInv
+	workload := math.Pow(qHat, 3.0) * muHatInv
 	if workload < 0.0 {
 		return math.MaxFloat64
 	}
@@ -160,6 +408,12 @@ func (b *LookAsideBalancer) calculateScore(node int64, cost *internalpb.CostAggr
 	return executeSpeed + workload
 }
 
+// estimatedQueueLength computes q̂_s = 1 + os·n + q_s, the instantaneous
+// sample fed into the queue-length EWMA.
+func estimatedQueueLength(outstanding, serverQueueLen int64, clusterSize int) float64 {
+	return 1 + float64(outstanding)*float64(clusterSize) + float64(serverQueueLen)
+}
+
 // if the node cost metrics hasn't been updated for a second, we think the metrics is too old
 func (b *LookAsideBalancer) isNodeCostMetricsTooOld(node int64) bool {
 	lastUpdateTs, ok := b.metricsUpdateTs.Get(node)
@@ -194,38 +448,39 @@ func (b *LookAsideBalancer) checkQueryNodeHealthLoop(ctx context.Context) {
 						ctx, cancel := context.WithTimeout(context.Background(), checkInterval)
 						defer cancel()
 
-						setUnreachable := func() bool {
-							return b.unreachableQueryNodes.Insert(node)
+						cb := b.getOrCreateCircuitBreaker(node)
+						// while the breaker is open or half-open, only spend a health check on
+						// this node when allow() would let real traffic through anyway, so the
+						// active probe and the passive probe share the same cool-down budget.
+						if cb.currentState() != circuitClosed && !cb.allow() {
+							return struct{}{}, nil
+						}
+
+						reportUnreachable := func(reason string, err error) {
+							cb.reportFailure()
+							log.Warn("query node health check failed", zap.String("reason", reason), zap.Int64("node", node), zap.Error(err))
 						}
 
 						qn, err := b.clientMgr.GetClient(ctx, node)
 						if err != nil {
-							if setUnreachable() {
-								log.Warn("get client failed, set node unreachable", zap.Int64("node", node), zap.Error(err))
-							}
+							reportUnreachable("get client failed", err)
 							return struct{}{}, nil
 						}
 
 						resp, err := qn.GetComponentStates(ctx)
 						if err != nil {
-							if setUnreachable() {
-								log.Warn("get component status failed,set node unreachable", zap.Int64("node", node), zap.Error(err))
-							}
+							reportUnreachable("get component status failed", err)
 							return struct{}{}, nil
 						}
 
 						if resp.GetState().GetStateCode() != commonpb.StateCode_Healthy {
-							if setUnreachable() {
-								log.Warn("component status unhealthy,set node unreachable", zap.Int64("node", node), zap.Error(err))
-							}
+							reportUnreachable("component status unhealthy", merr.WrapErrServiceUnavailable(resp.GetState().GetStateCode().String()))
 							return struct{}{}, nil
 						}
 
 						// check health successfully, update check health ts
 						b.metricsUpdateTs.Insert(node, time.Now().Local().UnixMilli())
-						if b.unreachableQueryNodes.TryRemove(node) {
-							log.Info("component recuperated, set node reachable", zap.Int64("node", node), zap.Error(err))
-						}
+						cb.reportSuccess()
 
 						return struct{}{}, nil
 					}))
@@ -0,0 +1,123 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+// TestLookAsideBalancer_CubicTermDominates reproduces the heavy-tail scenario
+// the C3 paper is designed to catch: one node's queue backs up (a GC pause,
+// a slow compaction, ...) while its raw response time still looks fine. The
+// cubic q̂_s term should make that node's score blow past a lightly-loaded
+// node long before response time alone would flag it.
+func TestLookAsideBalancer_CubicTermDominates(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+	ctx := context.Background()
+
+	healthyNode, backedUpNode := int64(1), int64(2)
+	const collectionID = int64(100)
+
+	// both nodes report a similar response/service time, but node 2 has a
+	// much larger outstanding queue.
+	for i := 0; i < 5; i++ {
+		balancer.UpdateCostMetrics(healthyNode, &internalpb.CostAggregation{
+			ResponseTime: 12,
+			ServiceTime:  10,
+			TotalNQ:      1,
+		}, collectionID)
+		balancer.UpdateCostMetrics(backedUpNode, &internalpb.CostAggregation{
+			ResponseTime: 13,
+			ServiceTime:  10,
+			TotalNQ:      50,
+		}, collectionID)
+	}
+
+	healthyScore := balancer.calculateScore(healthyNode, &internalpb.CostAggregation{ResponseTime: 12, ServiceTime: 10, TotalNQ: 1}, 0, 2)
+	backedUpScore := balancer.calculateScore(backedUpNode, &internalpb.CostAggregation{ResponseTime: 13, ServiceTime: 10, TotalNQ: 50}, 0, 2)
+
+	assert.Greater(t, backedUpScore, healthyScore)
+
+	node, err := balancer.SelectNode(ctx, []int64{healthyNode, backedUpNode}, 1, collectionID)
+	assert.NoError(t, err)
+	assert.Equal(t, healthyNode, node)
+}
+
+func TestLookAsideBalancer_RateControlThrottlesFastSender(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+
+	stat := balancer.getOrCreateNodeCostStat(1)
+	stat.muHatInv = 100 // 10 req/s service rate
+	stat.sendRate = 50  // proxy is sending far faster than the node can drain
+
+	assert.True(t, balancer.isNodeRateThrottled(1))
+
+	stat.sendRate = 1
+	assert.False(t, balancer.isNodeRateThrottled(1))
+}
+
+// TestLookAsideBalancer_PerCollectionScoreIsolation verifies that a slow
+// collection on a node doesn't drag down the score a different collection
+// sees for that same node.
+func TestLookAsideBalancer_PerCollectionScoreIsolation(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+	ctx := context.Background()
+	node := int64(1)
+	slowCollection, fastCollection := int64(10), int64(20)
+
+	balancer.UpdateCostMetrics(node, &internalpb.CostAggregation{
+		ResponseTime: 1000,
+		ServiceTime:  900,
+		TotalNQ:      500,
+	}, slowCollection)
+	balancer.UpdateCostMetrics(node, &internalpb.CostAggregation{
+		ResponseTime: 12,
+		ServiceTime:  10,
+		TotalNQ:      1,
+	}, fastCollection)
+
+	selected, err := balancer.SelectNode(ctx, []int64{node}, 1, fastCollection)
+	assert.NoError(t, err)
+	assert.Equal(t, node, selected)
+
+	cost, _ := balancer.getNodeCollectionMetrics(node, fastCollection)
+	assert.EqualValues(t, 10, cost.GetServiceTime())
+}
+
+// TestLookAsideBalancer_UnknownCollectionFallsBackToAggregate checks that
+// SelectNode still works for a collection with no per-collection data yet by
+// falling back to the node-wide aggregate metrics.
+func TestLookAsideBalancer_UnknownCollectionFallsBackToAggregate(t *testing.T) {
+	balancer := NewLookAsideBalancer(nil)
+	ctx := context.Background()
+	node := int64(1)
+
+	balancer.UpdateCostMetrics(node, &internalpb.CostAggregation{
+		ResponseTime: 12,
+		ServiceTime:  10,
+		TotalNQ:      1,
+	}, 10)
+
+	selected, err := balancer.SelectNode(ctx, []int64{node}, 1, 999)
+	assert.NoError(t, err)
+	assert.Equal(t, node, selected)
+}
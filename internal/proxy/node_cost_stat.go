@@ -0,0 +1,109 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+// nodeCostStat keeps the exponentially-weighted moving averages the C3 score
+// and the rate-control layer need per query node: the estimated queue
+// length q̂_s, the service time 1/μ̂_s, and the rate at which the proxy is
+// sending requests to / receiving responses from the node.
+type nodeCostStat struct {
+	mu sync.Mutex
+
+	qHat        float64
+	muHatInv    float64
+	sendRate    float64
+	receiveRate float64
+
+	lastSendTs int64
+	lastRecvTs int64
+}
+
+func newNodeCostStat() *nodeCostStat {
+	return &nodeCostStat{}
+}
+
+// recordSend updates the send-rate EWMA every time SelectNode dispatches a
+// request to this node.
+func (s *nodeCostStat) recordSend() {
+	alpha := metricsEWMAAlpha()
+	now := time.Now().UnixMilli()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastSendTs > 0 {
+		if dt := float64(now-s.lastSendTs) / 1000.0; dt > 0 {
+			s.sendRate = ewma(s.sendRate, 1.0/dt, alpha)
+		}
+	}
+	s.lastSendTs = now
+}
+
+// recordReceive folds a freshly reported CostAggregation into the queue
+// length, service time and receive-rate EWMAs.
+func (s *nodeCostStat) recordReceive(outstanding int64, clusterSize int, cost *internalpb.CostAggregation, alpha float64) {
+	now := time.Now().UnixMilli()
+	qSample := estimatedQueueLength(outstanding, cost.GetTotalNQ(), clusterSize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.qHat = ewma(s.qHat, qSample, alpha)
+	s.muHatInv = ewma(s.muHatInv, float64(cost.GetServiceTime()), alpha)
+	if s.lastRecvTs > 0 {
+		if dt := float64(now-s.lastRecvTs) / 1000.0; dt > 0 {
+			s.receiveRate = ewma(s.receiveRate, 1.0/dt, alpha)
+		}
+	}
+	s.lastRecvTs = now
+}
+
+// isThrottled reports whether the proxy is currently sending to this node
+// faster than its estimated service rate μ̂_s can drain, meaning SelectNode
+// should prefer another candidate until the backlog clears.
+func (s *nodeCostStat) isThrottled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.muHatInv <= 0 {
+		return false
+	}
+	// muHatInv is a service time in milliseconds while sendRate/receiveRate
+	// are expressed in requests per second, so convert before comparing.
+	muHat := 1000.0 / s.muHatInv
+	return s.sendRate > muHat
+}
+
+func (s *nodeCostStat) snapshot() (qHat, muHatInv, sendRate, receiveRate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.qHat, s.muHatInv, s.sendRate, s.receiveRate
+}
+
+func ewma(old, sample, alpha float64) float64 {
+	if old == 0 {
+		return sample
+	}
+	return alpha*sample + (1-alpha)*old
+}
@@ -0,0 +1,119 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// PowerOfTwoChoicesBalancer implements the "power of two choices" load
+// balancing scheme: instead of scanning every candidate to find the single
+// best score (an O(N) cost per SelectNode call that gets expensive once a
+// cluster has hundreds of query nodes), it samples two candidates uniformly
+// at random and picks whichever has the lower C3 score. This keeps
+// SelectNode O(1) regardless of cluster size while still steering most
+// traffic away from overloaded nodes.
+type PowerOfTwoChoicesBalancer struct {
+	// query node -> workload latest metrics
+	metricsMap *typeutil.ConcurrentMap[int64, *internalpb.CostAggregation]
+
+	// query node -> total nq of requests which already sent but response hasn't received
+	executingTaskTotalNQ *typeutil.ConcurrentMap[int64, *atomic.Int64]
+}
+
+func NewPowerOfTwoChoicesBalancer() *PowerOfTwoChoicesBalancer {
+	return &PowerOfTwoChoicesBalancer{
+		metricsMap:           typeutil.NewConcurrentMap[int64, *internalpb.CostAggregation](),
+		executingTaskTotalNQ: typeutil.NewConcurrentMap[int64, *atomic.Int64](),
+	}
+}
+
+func (b *PowerOfTwoChoicesBalancer) Start(ctx context.Context) {}
+
+func (b *PowerOfTwoChoicesBalancer) Close() {}
+
+func (b *PowerOfTwoChoicesBalancer) SelectNode(ctx context.Context, availableNodes []int64, cost int64, collectionID int64) (int64, error) {
+	if len(availableNodes) == 0 {
+		return -1, merr.WrapErrServiceUnavailable("no available nodes to select")
+	}
+
+	firstIdx := rand.Intn(len(availableNodes))
+	candidate := availableNodes[firstIdx]
+	if len(availableNodes) > 1 {
+		// draw a second, distinct index so the two candidates are never the
+		// same node, otherwise this degenerates into a single random pick.
+		secondIdx := rand.Intn(len(availableNodes) - 1)
+		if secondIdx >= firstIdx {
+			secondIdx++
+		}
+		second := availableNodes[secondIdx]
+		if b.score(second) < b.score(candidate) {
+			candidate = second
+		}
+	}
+
+	executingNQ, ok := b.executingTaskTotalNQ.Get(candidate)
+	if !ok {
+		executingNQ = atomic.NewInt64(0)
+		b.executingTaskTotalNQ.Insert(candidate, executingNQ)
+	}
+	executingNQ.Add(cost)
+
+	return candidate, nil
+}
+
+// score computes a C3-style score from the node's latest reported metrics
+// alone. Unlike LookAsideBalancer.calculateScore it keeps no EWMAs and does
+// not scale the outstanding NQ by the cluster size.
+func (b *PowerOfTwoChoicesBalancer) score(node int64) float64 {
+	cost, _ := b.metricsMap.Get(node)
+	executingNQ, ok := b.executingTaskTotalNQ.Get(node)
+	if !ok {
+		executingNQ = atomic.NewInt64(0)
+	}
+
+	if cost == nil || cost.ResponseTime == 0 || cost.ServiceTime == 0 {
+		return math.Pow(float64(1+executingNQ.Load()), 3.0)
+	}
+
+	executeSpeed := float64(cost.ResponseTime) - float64(cost.ServiceTime)
+	workload := math.Pow(float64(1+cost.TotalNQ+executingNQ.Load()), 3.0) * float64(cost.ServiceTime)
+	if workload < 0.0 {
+		return math.MaxFloat64
+	}
+
+	return executeSpeed + workload
+}
+
+func (b *PowerOfTwoChoicesBalancer) CancelWorkload(node int64, nq int64, collectionID int64) {
+	totalNQ, ok := b.executingTaskTotalNQ.Get(node)
+	if ok {
+		totalNQ.Sub(nq)
+	}
+}
+
+func (b *PowerOfTwoChoicesBalancer) UpdateCostMetrics(node int64, cost *internalpb.CostAggregation, collectionID int64) {
+	b.metricsMap.Insert(node, cost)
+}
@@ -0,0 +1,73 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// shard balance policy names, configurable through ProxyCfg.ShardBalancePolicy
+const (
+	ShardBalancePolicyNameLookAside  = "look_aside"
+	ShardBalancePolicyNameRoundRobin = "round_robin"
+	ShardBalancePolicyNameLeastOutNQ = "least_outstanding"
+	ShardBalancePolicyNamePowerOfTwo = "power_of_two_choices"
+)
+
+// ShardBalancer selects which query node a shard-level search/query request
+// should be routed to, and tracks the in-flight workload so later selections
+// can take it into account.
+type ShardBalancer interface {
+	Start(ctx context.Context)
+	Close()
+
+	// SelectNode picks one of availableNodes to serve a request of the given
+	// cost(nq) against collectionID.
+	SelectNode(ctx context.Context, availableNodes []int64, cost int64, collectionID int64) (int64, error)
+
+	// CancelWorkload reverts the bookkeeping SelectNode performed once a request
+	// finishes or is canceled.
+	CancelWorkload(node int64, nq int64, collectionID int64)
+
+	// UpdateCostMetrics records the latest cost metrics a query node reported for
+	// a previously dispatched request against collectionID.
+	UpdateCostMetrics(node int64, cost *internalpb.CostAggregation, collectionID int64)
+}
+
+// NewShardBalancer builds the ShardBalancer configured through
+// paramtable.Get().ProxyCfg.ShardBalancePolicy, falling back to the original
+// look-aside C3 policy when unset or unrecognized.
+func NewShardBalancer(clientMgr shardClientMgr) ShardBalancer {
+	policy := paramtable.Get().ProxyCfg.ShardBalancePolicy.GetValue()
+	switch policy {
+	case ShardBalancePolicyNameRoundRobin:
+		return NewRoundRobinBalancer()
+	case ShardBalancePolicyNameLeastOutNQ:
+		return NewLeastOutstandingBalancer()
+	case ShardBalancePolicyNamePowerOfTwo:
+		return NewPowerOfTwoChoicesBalancer()
+	case ShardBalancePolicyNameLookAside, "":
+		return NewLookAsideBalancer(clientMgr)
+	default:
+		log.Warn("unknown shard balance policy, fallback to look_aside")
+		return NewLookAsideBalancer(clientMgr)
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+// benchmarkCandidates builds an availableNodes slice the size of a cluster
+// with hundreds of query nodes, used to compare the O(N) full-scan
+// LookAsideBalancer against the other policies.
+func benchmarkCandidates(n int) []int64 {
+	nodes := make([]int64, n)
+	for i := range nodes {
+		nodes[i] = int64(i)
+	}
+	return nodes
+}
+
+const benchmarkCollectionID = int64(1)
+
+func benchmarkSelectNode(b *testing.B, balancer ShardBalancer, nodeCount int) {
+	nodes := benchmarkCandidates(nodeCount)
+	for _, node := range nodes {
+		balancer.UpdateCostMetrics(node, &internalpb.CostAggregation{
+			ResponseTime: 10,
+			ServiceTime:  5,
+			TotalNQ:      1,
+		}, benchmarkCollectionID)
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node, err := balancer.SelectNode(ctx, nodes, 1, benchmarkCollectionID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		balancer.CancelWorkload(node, 1, benchmarkCollectionID)
+	}
+}
+
+func BenchmarkLookAsideBalancer_SelectNode_200Nodes(b *testing.B) {
+	balancer := NewLookAsideBalancer(nil)
+	benchmarkSelectNode(b, balancer, 200)
+}
+
+func BenchmarkPowerOfTwoChoicesBalancer_SelectNode_200Nodes(b *testing.B) {
+	balancer := NewPowerOfTwoChoicesBalancer()
+	benchmarkSelectNode(b, balancer, 200)
+}
+
+func BenchmarkRoundRobinBalancer_SelectNode_200Nodes(b *testing.B) {
+	balancer := NewRoundRobinBalancer()
+	benchmarkSelectNode(b, balancer, 200)
+}
+
+func BenchmarkLeastOutstandingBalancer_SelectNode_200Nodes(b *testing.B) {
+	balancer := NewLeastOutstandingBalancer()
+	benchmarkSelectNode(b, balancer, 200)
+}